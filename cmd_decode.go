@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+)
+
+// runDecode decodes a vpn:// key and prints the embedded api_endpoint/api_key
+// JSON, without performing any registration. Useful for inspecting a key
+// before handing it to fetch or export.
+func runDecode(args []string) error {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	key := fs.String("key", "", "The vpn:// key to decode (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *key == "" {
+		fs.Usage()
+		return fmt.Errorf("the -key flag is required")
+	}
+
+	decoded, err := decodeAndParse(*key)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(decoded, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(out))
+	return nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mdp/qrterminal/v3"
+
+	"github.com/clicklord/amnezia-config-reader/transport"
+)
+
+const (
+	pairPollInterval = 2 * time.Second
+	pairPollTimeout  = 5 * time.Minute
+)
+
+// Errors returned by the pairing handshake.
+var (
+	ErrPairingDenied      = errors.New("pairing request was denied")
+	ErrPairingTimeout     = errors.New("timed out waiting for pairing approval")
+	ErrBadServerSignature = errors.New("server signature verification failed")
+)
+
+// pairRequest is POSTed to the poll endpoint. The printed pairing URL
+// carries the same public key and nonce as query parameters.
+type pairRequest struct {
+	PublicKey string `json:"public_key"`
+	Nonce     string `json:"nonce"`
+}
+
+// pairPollResponse is returned by the poll endpoint. Payload and Signature
+// are only populated once Status is "approved".
+type pairPollResponse struct {
+	Status    string `json:"status"`
+	Payload   string `json:"payload,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// runPair pairs this device with an Amnezia server without a vpn:// key:
+// it generates a key pair, prints a pairing URL (and QR code) carrying the
+// public key and a nonce, polls the server until an admin approves it, and
+// verifies the server's signature over the returned config against a
+// pinned public key before trusting it.
+func runPair(args []string) error {
+	fs := flag.NewFlagSet("pair", flag.ExitOnError)
+	server := fs.String("server", "", "Base URL of the Amnezia pairing API (required)")
+	serverPubKey := fs.String("server-pubkey", "", "Base64-encoded Ed25519 public key pinned for this server (required)")
+	outDir := fs.String("out", ".", "Directory to write the rendered per-container configs to")
+	proxyURL, domainFront := addTransportFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *server == "" || *serverPubKey == "" {
+		fs.Usage()
+		return fmt.Errorf("the -server and -server-pubkey flags are required")
+	}
+
+	client, err := buildTransportClient(*proxyURL, *domainFront)
+	if err != nil {
+		return err
+	}
+
+	pinnedKey, err := base64.StdEncoding.DecodeString(*serverPubKey)
+	if err != nil {
+		return fmt.Errorf("invalid -server-pubkey: %v", err)
+	}
+	if len(pinnedKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("-server-pubkey must be a %d-byte Ed25519 key", ed25519.PublicKeySize)
+	}
+
+	wgPrivateKey, wgPublicKey, err := GenerateX25519KeyPair()
+	if err != nil {
+		return err
+	}
+
+	nonce, err := generatePairingNonce()
+	if err != nil {
+		return err
+	}
+
+	pairURL := buildPairingURL(*server, wgPublicKey, nonce)
+	fmt.Println("Approve this device from your Amnezia admin panel:")
+	fmt.Println(pairURL)
+	fmt.Println()
+	qrterminal.GenerateWithConfig(pairURL, qrterminal.Config{
+		Writer:    os.Stdout,
+		Level:     qrterminal.M,
+		BlackChar: qrterminal.BLACK,
+		WhiteChar: qrterminal.WHITE,
+		QuietZone: 1,
+	})
+
+	payload, signature, err := pollForApproval(client, *server, wgPublicKey, nonce)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(pinnedKey), payload, signature) {
+		return ErrBadServerSignature
+	}
+
+	var config Config
+	if err := json.Unmarshal(payload, &config); err != nil {
+		return fmt.Errorf("failed to parse paired config: %v", err)
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	for i, container := range config.Containers {
+		rendered, err := renderContainerWithWireGuardKey(container, wgPrivateKey)
+		if err != nil {
+			log.Printf("skipping container %d (%s): %v", i, container.Type, err)
+			continue
+		}
+
+		outPath := filepath.Join(*outDir, fmt.Sprintf("%d-%s.conf", i, rendered.Type))
+		if err := os.WriteFile(outPath, []byte(rendered.Config), 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %v", outPath, err)
+		}
+
+		fmt.Printf("\nWrote %s container to %s\n", rendered.Type, outPath)
+	}
+
+	return nil
+}
+
+func generatePairingNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate pairing nonce: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func buildPairingURL(server, publicKey, nonce string) string {
+	v := url.Values{}
+	v.Set("public_key", publicKey)
+	v.Set("nonce", nonce)
+	return fmt.Sprintf("%s/pair?%s", strings.TrimRight(server, "/"), v.Encode())
+}
+
+// pollForApproval polls the server's pairing poll endpoint until the
+// request is approved or denied, or pairPollTimeout elapses. On approval it
+// returns the raw (still base64-decoded) config payload and its signature.
+func pollForApproval(client *transport.Client, server, publicKey, nonce string) (payload []byte, signature []byte, err error) {
+	pollURL := strings.TrimRight(server, "/") + "/pair/poll"
+	request := pairRequest{PublicKey: publicKey, Nonce: nonce}
+
+	deadline := time.Now().Add(pairPollTimeout)
+	for time.Now().Before(deadline) {
+		var resp pairPollResponse
+		if err := sendPostRequest(client, pollURL, request, "", &resp); err != nil {
+			time.Sleep(pairPollInterval)
+			continue
+		}
+
+		switch resp.Status {
+		case "approved":
+			payloadBytes, err := base64.StdEncoding.DecodeString(resp.Payload)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to decode pairing payload: %v", err)
+			}
+			sigBytes, err := base64.StdEncoding.DecodeString(resp.Signature)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to decode pairing signature: %v", err)
+			}
+			return payloadBytes, sigBytes, nil
+		case "denied":
+			return nil, nil, ErrPairingDenied
+		default:
+			time.Sleep(pairPollInterval)
+		}
+	}
+
+	return nil, nil, ErrPairingTimeout
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/clicklord/amnezia-config-reader/store"
+)
+
+// defaultVaultDir is used whenever a store command doesn't override -vault.
+const defaultVaultDir = ".amnezia-vault"
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	vaultDir := fs.String("vault", defaultVaultDir, "Vault directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	names, err := store.NewVault(*vaultDir).List()
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		fmt.Println("No saved profiles.")
+		return nil
+	}
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+func runShow(args []string) error {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	vaultDir := fs.String("vault", defaultVaultDir, "Vault directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: show <profile>")
+	}
+
+	passphrase, err := readPassphrase("Passphrase: ")
+	if err != nil {
+		return err
+	}
+
+	profile, err := store.NewVault(*vaultDir).Load(fs.Arg(0), passphrase)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Profile:   %s\nSaved:     %s\n\n%s\n",
+		profile.Name, profile.CreatedAt.Format("2006-01-02T15:04:05Z07:00"), string(profile.RawConfig))
+	return nil
+}
+
+func runRotate(args []string) error {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	vaultDir := fs.String("vault", defaultVaultDir, "Vault directory")
+	proxyURL, domainFront := addTransportFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: rotate <profile>")
+	}
+	name := fs.Arg(0)
+
+	passphrase, err := readPassphrase("Passphrase: ")
+	if err != nil {
+		return err
+	}
+
+	vault := store.NewVault(*vaultDir)
+	profile, err := vault.Load(name, passphrase)
+	if err != nil {
+		return err
+	}
+
+	client, err := buildTransportClient(*proxyURL, *domainFront)
+	if err != nil {
+		return err
+	}
+
+	config, wgPrivateKey, err := fetchConfig(client, profile.VPNKey)
+	if err != nil {
+		return fmt.Errorf("failed to re-register profile %q: %v", name, err)
+	}
+
+	updated, err := buildProfile(name, profile.VPNKey, wgPrivateKey, config)
+	if err != nil {
+		return err
+	}
+
+	if err := vault.Save(*updated, passphrase); err != nil {
+		return err
+	}
+
+	fmt.Printf("Rotated profile %q\n", name)
+	return nil
+}
+
+func runDelete(args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	vaultDir := fs.String("vault", defaultVaultDir, "Vault directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: delete <profile>")
+	}
+
+	if err := store.NewVault(*vaultDir).Delete(fs.Arg(0)); err != nil {
+		return err
+	}
+
+	fmt.Printf("Deleted profile %q\n", fs.Arg(0))
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Errors reported by ParseConfigURI and parseHeaderedPayload.
+var (
+	// ErrShortInput is returned when the decoded payload is too short to
+	// contain the 4-byte header the original vpn:// format requires.
+	ErrShortInput = errors.New("vpn:// payload is too short to contain a header")
+	// ErrBadMagic is returned by parseHeaderedPayload when the bytes
+	// following the header can't be inflated as zlib. ParseConfigURI does
+	// not return it: it treats such a vpn:// key as the raw variant.
+	ErrBadMagic = errors.New("vpn:// payload has an unrecognized header")
+	// ErrSizeMismatch is returned when the header's declared uncompressed
+	// size doesn't match what actually came out of the zlib stream.
+	ErrSizeMismatch = errors.New("vpn:// payload's declared size does not match its decompressed content")
+)
+
+// base64Alphabets are tried in order until one decodes successfully, since
+// different Amnezia clients have emitted both URL-safe and standard
+// Base64, with and without padding.
+var base64Alphabets = []*base64.Encoding{
+	base64.URLEncoding,
+	base64.RawURLEncoding,
+	base64.StdEncoding,
+	base64.RawStdEncoding,
+}
+
+// ConfigURI is a decoded vpn:// (or vpn2://) key.
+type ConfigURI struct {
+	// HasHeader is true for the original vpn:// format, which prefixes a
+	// 4-byte big-endian uncompressed-size header before a zlib stream. The
+	// newer vpn2:// variant is raw Base64 with no header and no
+	// compression.
+	HasHeader bool
+	// Size is the header's declared uncompressed payload size. Only
+	// meaningful when HasHeader is true.
+	Size uint32
+	// Payload is the fully decoded, decompressed data: JSON describing
+	// either a server's api_endpoint/api_key or (in a nested decode) its
+	// containers.
+	Payload []byte
+}
+
+// ParseConfigURI decodes a vpn:// or vpn2:// key into its payload. A vpn://
+// key is normally the original header-plus-zlib format, but newer Amnezia
+// clients have been observed emitting the same vpn:// prefix over a raw,
+// uncompressed payload - so when the decoded bytes don't inflate as zlib,
+// ParseConfigURI falls back to treating them as raw instead of failing.
+// vpn2:// is kept as an explicit alias for the raw variant.
+func ParseConfigURI(raw string) (*ConfigURI, error) {
+	if rest, ok := strings.CutPrefix(raw, "vpn2://"); ok {
+		return parseRawConfigURI(rest)
+	}
+
+	decoded, err := decodeBase64AnyAlphabet(strings.TrimPrefix(raw, "vpn://"))
+	if err != nil {
+		return nil, err
+	}
+
+	uri, err := parseHeaderedPayload(decoded)
+	if errors.Is(err, ErrBadMagic) {
+		return &ConfigURI{HasHeader: false, Payload: decoded}, nil
+	}
+	return uri, err
+}
+
+func parseRawConfigURI(encoded string) (*ConfigURI, error) {
+	decoded, err := decodeBase64AnyAlphabet(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return &ConfigURI{HasHeader: false, Payload: decoded}, nil
+}
+
+// parseHeaderedPayload interprets decoded as the original vpn:// format: a
+// 4-byte big-endian uncompressed-size header followed by a zlib stream.
+func parseHeaderedPayload(decoded []byte) (*ConfigURI, error) {
+	if len(decoded) < 4 {
+		return nil, ErrShortInput
+	}
+
+	size := binary.BigEndian.Uint32(decoded[:4])
+
+	zlibReader, err := zlib.NewReader(bytes.NewReader(decoded[4:]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadMagic, err)
+	}
+	defer zlibReader.Close()
+
+	payload, err := io.ReadAll(zlibReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress vpn:// payload: %v", err)
+	}
+
+	if uint32(len(payload)) != size {
+		return nil, fmt.Errorf("%w: header declared %d bytes, got %d", ErrSizeMismatch, size, len(payload))
+	}
+
+	return &ConfigURI{HasHeader: true, Size: size, Payload: payload}, nil
+}
+
+func decodeBase64AnyAlphabet(encoded string) ([]byte, error) {
+	var lastErr error
+	for _, enc := range base64Alphabets {
+		decoded, err := enc.DecodeString(encoded)
+		if err == nil {
+			return decoded, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("failed to decode Base64 payload in any known alphabet: %v", lastErr)
+}
+
+// Encode serializes c back into a vpn:// or vpn2:// key, the inverse of
+// ParseConfigURI. Headered URIs round-trip through zlib and URL-safe,
+// padded Base64 - the original format's canonical encoding.
+func (c *ConfigURI) Encode() (string, error) {
+	if !c.HasHeader {
+		return "vpn2://" + base64.URLEncoding.EncodeToString(c.Payload), nil
+	}
+
+	var compressed bytes.Buffer
+	zlibWriter := zlib.NewWriter(&compressed)
+	if _, err := zlibWriter.Write(c.Payload); err != nil {
+		return "", fmt.Errorf("failed to compress payload: %v", err)
+	}
+	if err := zlibWriter.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress payload: %v", err)
+	}
+
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(len(c.Payload)))
+
+	return "vpn://" + base64.URLEncoding.EncodeToString(append(header, compressed.Bytes()...)), nil
+}
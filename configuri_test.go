@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestParseConfigURIHeaderedRoundTrip(t *testing.T) {
+	payload := []byte(`{"api_endpoint":"https://example.com","api_key":"secret"}`)
+
+	uri := &ConfigURI{HasHeader: true, Payload: payload}
+	encoded, err := uri.Encode()
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	parsed, err := ParseConfigURI(encoded)
+	if err != nil {
+		t.Fatalf("ParseConfigURI(%q): %v", encoded, err)
+	}
+	if !parsed.HasHeader {
+		t.Errorf("HasHeader = false, want true")
+	}
+	if string(parsed.Payload) != string(payload) {
+		t.Errorf("Payload = %q, want %q", parsed.Payload, payload)
+	}
+}
+
+func TestParseConfigURIRawVariant(t *testing.T) {
+	payload := []byte(`{"api_endpoint":"https://example.com","api_key":"secret"}`)
+	raw := "vpn://" + base64.URLEncoding.EncodeToString(payload)
+
+	parsed, err := ParseConfigURI(raw)
+	if err != nil {
+		t.Fatalf("ParseConfigURI(%q): %v", raw, err)
+	}
+	if parsed.HasHeader {
+		t.Errorf("HasHeader = true, want false")
+	}
+	if string(parsed.Payload) != string(payload) {
+		t.Errorf("Payload = %q, want %q", parsed.Payload, payload)
+	}
+}
+
+func TestParseConfigURIRawVariantVpn2Alias(t *testing.T) {
+	payload := []byte(`{"api_endpoint":"https://example.com","api_key":"secret"}`)
+	raw := "vpn2://" + base64.URLEncoding.EncodeToString(payload)
+
+	parsed, err := ParseConfigURI(raw)
+	if err != nil {
+		t.Fatalf("ParseConfigURI(%q): %v", raw, err)
+	}
+	if parsed.HasHeader {
+		t.Errorf("HasHeader = true, want false")
+	}
+	if string(parsed.Payload) != string(payload) {
+		t.Errorf("Payload = %q, want %q", parsed.Payload, payload)
+	}
+}
+
+func TestParseConfigURIShortInput(t *testing.T) {
+	raw := "vpn://" + base64.URLEncoding.EncodeToString([]byte{1, 2})
+
+	_, err := ParseConfigURI(raw)
+	if !errors.Is(err, ErrShortInput) {
+		t.Fatalf("err = %v, want ErrShortInput", err)
+	}
+}
+
+func TestParseConfigURISizeMismatch(t *testing.T) {
+	uri := &ConfigURI{HasHeader: true, Payload: []byte("hello world")}
+	encoded, err := uri.Encode()
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	decoded, err := base64.URLEncoding.DecodeString(encoded[len("vpn://"):])
+	if err != nil {
+		t.Fatalf("decoding test fixture: %v", err)
+	}
+	binary.BigEndian.PutUint32(decoded[:4], 999)
+	corrupted := "vpn://" + base64.URLEncoding.EncodeToString(decoded)
+
+	_, err = ParseConfigURI(corrupted)
+	if !errors.Is(err, ErrSizeMismatch) {
+		t.Fatalf("err = %v, want ErrSizeMismatch", err)
+	}
+}
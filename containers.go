@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ContainerType identifies one of the VPN protocols an Amnezia server can run.
+type ContainerType string
+
+const (
+	ContainerOpenVPN     ContainerType = "openvpn"
+	ContainerShadowSocks ContainerType = "shadowsocks"
+	ContainerCloak       ContainerType = "cloak"
+	ContainerWireGuard   ContainerType = "wireguard"
+	ContainerIKEv2       ContainerType = "ikev2"
+	ContainerAWG         ContainerType = "awg"
+)
+
+// ProtocolConfig holds the raw, server-rendered config for a single protocol.
+// Amnezia returns this config as a JSON string containing (among other things)
+// a "config" field with placeholders the client is expected to fill in.
+type ProtocolConfig struct {
+	LastConfig string `json:"last_config"`
+}
+
+// Container describes one VPN container attached to the user's Amnezia
+// server. Only the field matching Type is populated.
+type Container struct {
+	Type ContainerType `json:"container"`
+
+	OpenVPN     *ProtocolConfig `json:"openvpn,omitempty"`
+	ShadowSocks *ProtocolConfig `json:"shadowsocks,omitempty"`
+	Cloak       *ProtocolConfig `json:"cloak,omitempty"`
+	WireGuard   *ProtocolConfig `json:"wireguard,omitempty"`
+	IKEv2       *ProtocolConfig `json:"ikev2,omitempty"`
+	AWG         *ProtocolConfig `json:"awg,omitempty"`
+}
+
+// protocolConfig returns the ProtocolConfig matching c.Type, regardless of
+// which struct field it actually lives in.
+func (c Container) protocolConfig() (*ProtocolConfig, error) {
+	var pc *ProtocolConfig
+	switch c.Type {
+	case ContainerOpenVPN:
+		pc = c.OpenVPN
+	case ContainerShadowSocks:
+		pc = c.ShadowSocks
+	case ContainerCloak:
+		pc = c.Cloak
+	case ContainerWireGuard:
+		pc = c.WireGuard
+	case ContainerIKEv2:
+		pc = c.IKEv2
+	case ContainerAWG:
+		pc = c.AWG
+	default:
+		return nil, fmt.Errorf("unsupported container type: %q", c.Type)
+	}
+	if pc == nil {
+		return nil, fmt.Errorf("container reports type %q but has no matching config block", c.Type)
+	}
+	return pc, nil
+}
+
+// RenderedContainer is a fully substituted, ready-to-write config for one
+// container, plus whatever key material was generated for it.
+type RenderedContainer struct {
+	Type     ContainerType
+	Config   string
+	KeyPairs map[string]string
+}
+
+// renderContainerWithWireGuardKey generates any key material c.Type
+// requires, substitutes it into the container's config placeholders, and
+// returns the result. For WireGuard/AWG containers it substitutes
+// wgPrivateKey instead of generating a fresh key pair, when wgPrivateKey is
+// non-empty - every caller must pass the same key pair it already
+// registered with the server, since the server only authenticates the
+// public half it was handed during the registration handshake.
+func renderContainerWithWireGuardKey(c Container, wgPrivateKey string) (*RenderedContainer, error) {
+	pc, err := c.protocolConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var lastConfig map[string]interface{}
+	if err := json.Unmarshal([]byte(pc.LastConfig), &lastConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse last_config for %q: %v", c.Type, err)
+	}
+
+	configText, _ := lastConfig["config"].(string)
+
+	placeholders, err := generateKeyMaterial(c.Type, wgPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key material for %q: %v", c.Type, err)
+	}
+
+	for placeholder, value := range placeholders {
+		configText = strings.Replace(configText, placeholder, value, -1)
+	}
+
+	return &RenderedContainer{
+		Type:     c.Type,
+		Config:   configText,
+		KeyPairs: placeholders,
+	}, nil
+}
+
+// generateKeyMaterial produces the client-side secrets a given protocol
+// expects to be substituted into its config, keyed by placeholder name.
+// Protocols that hand out all client secrets server-side (ShadowSocks,
+// Cloak, IKEv2) need nothing generated locally. For WireGuard/AWG,
+// wgPrivateKey is reused instead of generating a new key pair when non-empty.
+func generateKeyMaterial(t ContainerType, wgPrivateKey string) (map[string]string, error) {
+	switch t {
+	case ContainerWireGuard, ContainerAWG:
+		privateKey := wgPrivateKey
+		if privateKey == "" {
+			generated, _, err := GenerateX25519KeyPair()
+			if err != nil {
+				return nil, err
+			}
+			privateKey = generated
+		}
+		return map[string]string{
+			"$WIREGUARD_CLIENT_PRIVATE_KEY": privateKey,
+		}, nil
+	case ContainerOpenVPN:
+		certPEM, keyPEM, err := GenerateOpenVPNClientCertRequest()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{
+			"$OPENVPN_CLIENT_CERT": certPEM,
+			"$OPENVPN_CLIENT_KEY":  keyPEM,
+		}, nil
+	case ContainerShadowSocks, ContainerCloak, ContainerIKEv2:
+		return map[string]string{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported container type: %q", t)
+	}
+}
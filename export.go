@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// runExport registers this device and, for each container, parses the
+// substituted config into typed structs and serializes it into the format
+// its client expects: wg-quick INI for WireGuard/AWG, inline .ovpn for
+// OpenVPN. Containers with no typed writer yet fall back to the raw
+// substituted config, same as fetch.
+//
+// The OpenVPN .ovpn this writes embeds an unsigned CSR in place of
+// <cert> - see exportContainer - so unlike the WireGuard/AWG output it is
+// not actually loadable by `openvpn --config` until the server signs it.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	key := fs.String("key", "", "The vpn:// key to process (required)")
+	outDir := fs.String("out", ".", "Directory to write the exported configs to")
+	proxyURL, domainFront := addTransportFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *key == "" {
+		fs.Usage()
+		return fmt.Errorf("the -key flag is required")
+	}
+
+	client, err := buildTransportClient(*proxyURL, *domainFront)
+	if err != nil {
+		return err
+	}
+
+	config, wgPrivateKey, err := fetchConfig(client, *key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	for i, container := range config.Containers {
+		rendered, err := renderContainerWithWireGuardKey(container, wgPrivateKey)
+		if err != nil {
+			log.Printf("skipping container %d (%s): %v", i, container.Type, err)
+			continue
+		}
+
+		content, ext, err := exportContainer(rendered)
+		if err != nil {
+			log.Printf("skipping container %d (%s): %v", i, container.Type, err)
+			continue
+		}
+
+		outPath := filepath.Join(*outDir, fmt.Sprintf("%d-%s%s", i, rendered.Type, ext))
+		if err := os.WriteFile(outPath, []byte(content), 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %v", outPath, err)
+		}
+
+		fmt.Printf("\nWrote %s container to %s\n", rendered.Type, outPath)
+		if rendered.Type == ContainerOpenVPN {
+			log.Printf("warning: %s embeds an unsigned certificate request, not a certificate - have the server sign it before use", outPath)
+		}
+	}
+
+	return nil
+}
+
+// exportContainer serializes a rendered container into its canonical
+// client-config format, returning the file extension it should be written
+// with.
+func exportContainer(rendered *RenderedContainer) (content string, ext string, err error) {
+	switch rendered.Type {
+	case ContainerWireGuard, ContainerAWG:
+		wgConfig, err := ParseWireGuardConfig(rendered.Config)
+		if err != nil {
+			return "", "", err
+		}
+		return wgConfig.Render(), ".conf", nil
+	case ContainerOpenVPN:
+		// rendered.Config's <cert> block is an unsigned CSR (see
+		// GenerateOpenVPNClientCertRequest) - the server hasn't signed one
+		// back to us yet, so this .ovpn isn't loadable as-is.
+		ovpnConfig, err := ParseOpenVPNConfig(rendered.Config)
+		if err != nil {
+			return "", "", err
+		}
+		return ovpnConfig.Render(), ".ovpn", nil
+	default:
+		// No typed writer for this protocol yet; fall back to the raw,
+		// already-substituted config.
+		return rendered.Config, ".conf", nil
+	}
+}
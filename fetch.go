@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/clicklord/amnezia-config-reader/store"
+	"github.com/clicklord/amnezia-config-reader/transport"
+)
+
+// runFetch registers this device with the Amnezia server embedded in a
+// vpn:// key and writes the raw, substituted config for each container to
+// -out. If -profile is given, the fetched config and generated key material
+// are also saved to an encrypted vault under that name.
+func runFetch(args []string) error {
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	key := fs.String("key", "", "The vpn:// key to process (required)")
+	outDir := fs.String("out", ".", "Directory to write the rendered per-container configs to")
+	profileName := fs.String("profile", "", "Save the fetched config and keys under this profile name")
+	vaultDir := fs.String("vault", defaultVaultDir, "Vault directory (used with -profile)")
+	proxyURL, domainFront := addTransportFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *key == "" {
+		fs.Usage()
+		return fmt.Errorf("the -key flag is required")
+	}
+
+	client, err := buildTransportClient(*proxyURL, *domainFront)
+	if err != nil {
+		return err
+	}
+
+	config, wgPrivateKey, err := fetchConfig(client, *key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	keyPairs := map[string]map[string]string{}
+
+	for i, container := range config.Containers {
+		rendered, err := renderContainerWithWireGuardKey(container, wgPrivateKey)
+		if err != nil {
+			log.Printf("skipping container %d (%s): %v", i, container.Type, err)
+			continue
+		}
+
+		outPath := filepath.Join(*outDir, fmt.Sprintf("%d-%s.conf", i, rendered.Type))
+		if err := os.WriteFile(outPath, []byte(rendered.Config), 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %v", outPath, err)
+		}
+
+		fmt.Printf("\nWrote %s container to %s\n", rendered.Type, outPath)
+		for placeholder, value := range rendered.KeyPairs {
+			fmt.Printf("%s: %s\n", strings.TrimPrefix(placeholder, "$"), value)
+		}
+
+		keyPairs[fmt.Sprintf("%d-%s", i, rendered.Type)] = rendered.KeyPairs
+	}
+
+	if *profileName != "" {
+		rawConfig, err := json.Marshal(config)
+		if err != nil {
+			return fmt.Errorf("failed to serialize config: %v", err)
+		}
+
+		passphrase, err := readPassphrase(fmt.Sprintf("Passphrase to encrypt profile %q: ", *profileName))
+		if err != nil {
+			return err
+		}
+
+		profile := store.Profile{
+			Name:      *profileName,
+			VPNKey:    *key,
+			RawConfig: rawConfig,
+			KeyPairs:  keyPairs,
+			CreatedAt: time.Now(),
+		}
+		if err := store.NewVault(*vaultDir).Save(profile, passphrase); err != nil {
+			return fmt.Errorf("failed to save profile %q: %v", *profileName, err)
+		}
+
+		fmt.Printf("\nSaved profile %q to %s\n", *profileName, *vaultDir)
+	}
+
+	return nil
+}
+
+// fetchConfig performs the registration handshake described by a vpn:// key
+// (decode the key, generate a registration keypair, POST it to the server
+// via client) and returns the decoded multi-container config it responds
+// with, along with the private half of the registration keypair. That
+// private key must be the one substituted into any WireGuard/AWG container
+// rendered from this config - the server only knows the public key it was
+// handed here, so a different key would never authenticate.
+func fetchConfig(client *transport.Client, key string) (*Config, string, error) {
+	decoded, err := decodeAndParse(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	privateKey, pubKey, err := GenerateX25519KeyPair()
+	if err != nil {
+		return nil, "", err
+	}
+
+	requestBody := RequestBody{
+		PublicKey:  pubKey,
+		OSVersion:  "macOS",
+		AppVersion: "4.8.2.3",
+		UUID:       uuid.New().String(),
+	}
+
+	var responseBody ResponseBody
+	if err := sendPostRequest(client, decoded.APIEndpoint, requestBody, decoded.APIKey, &responseBody); err != nil {
+		return nil, "", err
+	}
+
+	decodedConfig, err := decode(responseBody.Config)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var config Config
+	if err := json.Unmarshal(decodedConfig, &config); err != nil {
+		return nil, "", err
+	}
+
+	return &config, privateKey, nil
+}
+
+// buildProfile renders every container in config, substituting wgPrivateKey
+// into any WireGuard/AWG container, and packages the result into a
+// store.Profile ready to be saved. It is used by rotate, where
+// re-rendering with the newly re-registered key is the whole point.
+func buildProfile(name, vpnKey, wgPrivateKey string, config *Config) (*store.Profile, error) {
+	rawConfig, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize config: %v", err)
+	}
+
+	keyPairs := map[string]map[string]string{}
+	for i, container := range config.Containers {
+		rendered, err := renderContainerWithWireGuardKey(container, wgPrivateKey)
+		if err != nil {
+			log.Printf("skipping container %d (%s): %v", i, container.Type, err)
+			continue
+		}
+		keyPairs[fmt.Sprintf("%d-%s", i, rendered.Type)] = rendered.KeyPairs
+	}
+
+	return &store.Profile{
+		Name:      name,
+		VPNKey:    vpnKey,
+		RawConfig: rawConfig,
+		KeyPairs:  keyPairs,
+		CreatedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// GenerateX25519KeyPair generates a private and public key pair for X25519,
+// as used by the WireGuard and AmneziaWG containers.
+func GenerateX25519KeyPair() (privateKeyBase64 string, publicKeyBase64 string, err error) {
+	// Step 1: Generate a random private key (32 bytes)
+	privateKey := make([]byte, curve25519.ScalarSize)
+	_, err = rand.Read(privateKey)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate private key: %v", err)
+	}
+
+	// Clamp the private key according to X25519 requirements
+	privateKey[0] &= 248
+	privateKey[31] &= 127
+	privateKey[31] |= 64
+
+	// Step 2: Compute the corresponding public key
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to compute public key: %v", err)
+	}
+
+	// Step 3: Encode keys in Base64 for readability
+	privateKeyBase64 = base64.StdEncoding.EncodeToString(privateKey)
+	publicKeyBase64 = base64.StdEncoding.EncodeToString(publicKey)
+
+	return privateKeyBase64, publicKeyBase64, nil
+}
+
+// GenerateOpenVPNClientCertRequest generates an RSA keypair and a PKCS#10
+// certificate signing request for the OpenVPN client, PEM-encoded so it can
+// be substituted directly into the container's last_config template. The
+// server is expected to sign the CSR and return a usable client certificate;
+// until then the CSR itself is substituted in its place so the resulting
+// config is at least well-formed.
+func GenerateOpenVPNClientCertRequest() (csrPEM string, keyPEM string, err error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate RSA key: %v", err)
+	}
+
+	template := x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName: "amnezia-client",
+		},
+	}
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &template, privateKey)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create certificate request: %v", err)
+	}
+
+	csrPEM = string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE REQUEST",
+		Bytes: csrDER,
+	}))
+
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	}))
+
+	return csrPEM, keyPEM, nil
+}
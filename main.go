@@ -1,135 +1,79 @@
 package main
 
 import (
-	"bytes"
-	"compress/zlib"
-	"crypto/rand"
-	"encoding/base64"
+	"context"
 	"encoding/json"
-	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"strings"
+	"os"
 
-	"github.com/google/uuid"
-	"golang.org/x/crypto/curve25519"
+	"github.com/clicklord/amnezia-config-reader/transport"
 )
 
-// Define the request body struct
-type RequestBody struct {
-	PublicKey  string `json:"public_key"`
-	OSVersion  string `json:"os_version"`
-	AppVersion string `json:"app_version"`
-	UUID       string `json:"uuid"`
-}
-
-// Define the response struct
-type ResponseBody struct {
-	Config string `json:"config"`
-}
-
-type Config struct {
-	Containers []Container `json:"containers"`
-}
-
-type Container struct {
-	AWG AWG `json:"awg"`
-}
-
-type AWG struct {
-	LastConfig string `json:"last_config"`
-}
-
-type LastConfig struct {
-	Config map[string]interface{} `json:"config"`
-}
-
-// Define the structure for the parsed JSON
-type DecodedData struct {
-	APIEndpoint string `json:"api_endpoint"`
-	APIKey      string `json:"api_key"`
-}
-
 func main() {
-	// Define a string flag named "key" with a default value and a description
-	key := flag.String("key", "", "The key string to process")
-
-	// Parse the command-line arguments
-	flag.Parse()
-
-	// Check if the "key" parameter was provided
-	if key == nil || *key == "" {
-		fmt.Println("Error: The -key parameter is required.")
-		flag.Usage() // Print usage information
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+
+	cmd, args := os.Args[1], os.Args[2:]
+
+	var err error
+	switch cmd {
+	case "fetch":
+		err = runFetch(args)
+	case "export":
+		err = runExport(args)
+	case "decode":
+		err = runDecode(args)
+	case "list":
+		err = runList(args)
+	case "show":
+		err = runShow(args)
+	case "rotate":
+		err = runRotate(args)
+	case "delete":
+		err = runDelete(args)
+	case "pair":
+		err = runPair(args)
+	case "-h", "--help", "help":
+		usage()
 		return
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n", cmd)
+		usage()
+		os.Exit(1)
 	}
 
-	decoded, err := decodeAndParse(*key)
-	if err != nil {
-		log.Fatalf("Error: %v", err)
-	}
-
-	// Example URL and API key
-	url := decoded.APIEndpoint
-	apiKey := decoded.APIKey
-
-	pubKey, privateKey, err := GenerateX25519KeyPair()
-	if err != nil {
-		log.Fatalf("Error: %v", err)
-	}
-
-	// Prepare the request body
-	requestBody := RequestBody{
-		PublicKey:  pubKey,
-		OSVersion:  "macOS",
-		AppVersion: "4.8.2.3",
-		UUID:       uuid.New().String(),
-	}
-
-	// // Prepare a variable to store the response
-	var responseBody ResponseBody
-
-	// Call the sendPostRequest function
-	err = sendPostRequest(url, requestBody, apiKey, &responseBody)
-	if err != nil {
-		log.Fatalf("Error: %v", err)
-	}
-	decodedConfig, err := decode(responseBody.Config)
-	if err != nil {
-		log.Fatalf("Error: %v", err)
-	}
-
-	var config Config
-	err = json.Unmarshal(decodedConfig, &config)
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
+}
 
-	var lastConfig map[string]interface{}
-	err = json.Unmarshal([]byte(config.Containers[0].AWG.LastConfig), &lastConfig)
-	if err != nil {
-		log.Fatalf("Error: %v", err)
-	}
-
-	// Print the parsed response
-	fmt.Printf("\nPrivate key: %+v\n", privateKey)
-
-	resp := strings.Replace(lastConfig["config"].(string), "$WIREGUARD_CLIENT_PRIVATE_KEY", privateKey, -1)
-	fmt.Printf("\nResponse: \n%+v\n", resp)
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: %s <command> [flags]
+
+Commands:
+  fetch   register this device and print/write the raw per-container configs
+  export  register this device and write wg-quick .conf and inline .ovpn files
+          (an .ovpn embeds an unsigned certificate request, not a certificate)
+  decode  decode a vpn:// key and print the embedded JSON
+  list    list profiles saved in the vault
+  show    decrypt and print a saved profile
+  rotate  re-register a saved profile and replace its key material
+  delete  remove a profile from the vault
+  pair    pair with a server via a QR code instead of a vpn:// key
+`, os.Args[0])
 }
 
-// sendPostRequest sends a POST request to the specified URL with a JSON body and Authorization header.
+// sendPostRequest sends a POST request to the specified URL with a JSON body and Authorization header,
+// via client (retries, proxying, etc. are client's responsibility).
 // It parses the response into the provided result struct.
-func sendPostRequest(url string, body interface{}, apiKey string, response interface{}) error {
+func sendPostRequest(client *transport.Client, rawURL string, body interface{}, apiKey string, response interface{}) error {
 	// Validate input parameters
-	if url == "" {
+	if rawURL == "" {
 		return fmt.Errorf("URL cannot be empty")
 	}
-	if apiKey == "" {
-		return fmt.Errorf("API key cannot be empty")
-	}
 
 	// Serialize the body into JSON
 	jsonBody, err := json.Marshal(body)
@@ -137,71 +81,29 @@ func sendPostRequest(url string, body interface{}, apiKey string, response inter
 		return fmt.Errorf("failed to serialize request body: %v", err)
 	}
 
-	// Create a new HTTP POST request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %v", err)
+	headers := map[string]string{"Content-Type": "application/json"}
+	if apiKey != "" {
+		headers["Authorization"] = "Api-Key " + apiKey
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Api-Key "+apiKey)
-
-	// Send the request using the default HTTP client
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := client.Post(context.Background(), rawURL, jsonBody, headers)
 	if err != nil {
 		return fmt.Errorf("failed to send HTTP request: %v", err)
 	}
 	defer resp.Body.Close()
 
-	// Check the HTTP response status code
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected HTTP status code: %d, Response: %s", resp.StatusCode, string(bodyBytes))
+		return fmt.Errorf("unexpected HTTP status code: %d", resp.StatusCode)
 	}
 
 	// Read and parse the response body into the result struct
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %v", err)
-	}
-
-	err = json.Unmarshal(respBody, response)
-	if err != nil {
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
 		return fmt.Errorf("failed to parse response JSON: %v", err)
 	}
 
 	return nil
 }
 
-// GenerateX25519KeyPair generates a private and public key pair for X25519.
-func GenerateX25519KeyPair() (privateKeyBase64 string, publicKeyBase64 string, err error) {
-	// Step 1: Generate a random private key (32 bytes)
-	privateKey := make([]byte, curve25519.ScalarSize)
-	_, err = rand.Read(privateKey)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to generate private key: %v", err)
-	}
-
-	// Clamp the private key according to X25519 requirements
-	privateKey[0] &= 248
-	privateKey[31] &= 127
-	privateKey[31] |= 64
-
-	// Step 2: Compute the corresponding public key
-	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to compute public key: %v", err)
-	}
-
-	// Step 3: Encode keys in Base64 for readability
-	privateKeyBase64 = base64.StdEncoding.EncodeToString(privateKey)
-	publicKeyBase64 = base64.StdEncoding.EncodeToString(publicKey)
-
-	return privateKeyBase64, publicKeyBase64, nil
-}
-
 // decodeAndParse decodes a Base64 (URL-safe) string, decompresses it using zlib,
 // and parses the result into a JSON object with api_endpoint and api_key fields.
 func decodeAndParse(encodedString string) (*DecodedData, error) {
@@ -220,26 +122,11 @@ func decodeAndParse(encodedString string) (*DecodedData, error) {
 	return &decodedData, nil
 }
 
+// decode decodes a vpn:// (or vpn2://) key into its raw payload bytes.
 func decode(encodedString string) ([]byte, error) {
-	encodedString = strings.Replace(encodedString, "vpn://", "", -1)
-
-	// Step 1: Decode the Base64 (URL-safe) string
-	decodedBytes, err := base64.URLEncoding.DecodeString(encodedString)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode Base64 string: %v", err)
-	}
-
-	// Step 2: Decompress the decoded bytes using zlib
-	zlibReader, err := zlib.NewReader(bytes.NewReader(decodedBytes[4:]))
+	uri, err := ParseConfigURI(encodedString)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create zlib reader: %v", err)
+		return nil, err
 	}
-	defer zlibReader.Close()
-
-	decompressedBytes, err := io.ReadAll(zlibReader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decompress data: %v", err)
-	}
-
-	return decompressedBytes, nil
+	return uri.Payload, nil
 }
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/clicklord/amnezia-config-reader/transport"
+)
+
+// addTransportFlags registers the -proxy and -domain-front flags shared by
+// every subcommand that talks to the network.
+func addTransportFlags(fs *flag.FlagSet) (proxyURL, domainFront *string) {
+	proxyURL = fs.String("proxy", "", "Proxy URL to dial through, e.g. socks5://127.0.0.1:9050 to ride over Tor")
+	domainFront = fs.String("domain-front", "", "Host to dial and send via TLS SNI; the HTTP Host header still names the real server (domain fronting)")
+	return proxyURL, domainFront
+}
+
+// buildTransportClient constructs a transport.Client from flag values
+// registered by addTransportFlags.
+func buildTransportClient(proxyURL, domainFront string) (*transport.Client, error) {
+	return transport.NewClient(transport.Config{
+		ProxyURL:    proxyURL,
+		DomainFront: domainFront,
+	})
+}
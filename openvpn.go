@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OpenVPNConfig is a typed, round-trippable representation of an inline
+// .ovpn client config: the handful of directives export cares about, plus
+// everything else preserved verbatim so nothing the server sent is lost.
+type OpenVPNConfig struct {
+	Remote string
+	Port   string
+	Proto  string
+	Cipher string
+	Auth   string
+	CA     string
+	Cert   string
+	Key    string
+	Extra  []string
+}
+
+// ParseOpenVPNConfig parses an inline OpenVPN client config (with
+// placeholders already substituted) into a typed OpenVPNConfig.
+func ParseOpenVPNConfig(raw string) (*OpenVPNConfig, error) {
+	cfg := &OpenVPNConfig{}
+
+	lines := strings.Split(raw, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+
+		if block, consumed := parseInlineBlock(lines, i); block != "" || consumed > 0 {
+			switch {
+			case strings.HasPrefix(line, "<ca>"):
+				cfg.CA = block
+			case strings.HasPrefix(line, "<cert>"):
+				cfg.Cert = block
+			case strings.HasPrefix(line, "<key>"):
+				cfg.Key = block
+			default:
+				cfg.Extra = append(cfg.Extra, lines[i:i+consumed]...)
+			}
+			i += consumed - 1
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "remote":
+			if cfg.Remote != "" || len(fields) > 3 {
+				// Additional remotes, and remotes with their own proto,
+				// are kept verbatim.
+				cfg.Extra = append(cfg.Extra, line)
+				continue
+			}
+			if len(fields) > 1 {
+				cfg.Remote = fields[1]
+			}
+			if len(fields) > 2 {
+				cfg.Port = fields[2]
+			}
+		case "proto":
+			if len(fields) > 1 {
+				cfg.Proto = fields[1]
+			}
+		case "cipher":
+			if len(fields) > 1 {
+				cfg.Cipher = fields[1]
+			}
+		case "auth":
+			if len(fields) > 1 {
+				cfg.Auth = fields[1]
+			}
+		default:
+			cfg.Extra = append(cfg.Extra, line)
+		}
+	}
+
+	return cfg, nil
+}
+
+// parseInlineBlock, given the index of a <tag> line, returns the contents
+// between it and the matching </tag> line (exclusive) and how many lines the
+// whole block (open tag, body, close tag) spans. It returns ("", 0) if line
+// i does not open a recognized inline block.
+func parseInlineBlock(lines []string, i int) (string, int) {
+	open := strings.TrimSpace(lines[i])
+	if !strings.HasPrefix(open, "<") || strings.HasPrefix(open, "</") {
+		return "", 0
+	}
+	tag := strings.Trim(open, "<>")
+	closeTag := "</" + tag + ">"
+
+	var body []string
+	for j := i + 1; j < len(lines); j++ {
+		if strings.TrimSpace(lines[j]) == closeTag {
+			return strings.Join(body, "\n"), j - i + 1
+		}
+		body = append(body, lines[j])
+	}
+
+	return "", 0
+}
+
+// Render serializes the config back into the canonical inline .ovpn format,
+// ready to be passed to `openvpn --config`.
+func (c *OpenVPNConfig) Render() string {
+	var b strings.Builder
+
+	if c.Remote != "" {
+		if c.Port != "" {
+			fmt.Fprintf(&b, "remote %s %s\n", c.Remote, c.Port)
+		} else {
+			fmt.Fprintf(&b, "remote %s\n", c.Remote)
+		}
+	}
+	if c.Proto != "" {
+		fmt.Fprintf(&b, "proto %s\n", c.Proto)
+	}
+	if c.Cipher != "" {
+		fmt.Fprintf(&b, "cipher %s\n", c.Cipher)
+	}
+	if c.Auth != "" {
+		fmt.Fprintf(&b, "auth %s\n", c.Auth)
+	}
+	for _, line := range c.Extra {
+		fmt.Fprintln(&b, line)
+	}
+
+	writeInlineBlock(&b, "ca", c.CA)
+	writeInlineBlock(&b, "cert", c.Cert)
+	writeInlineBlock(&b, "key", c.Key)
+
+	return b.String()
+}
+
+func writeInlineBlock(b *strings.Builder, tag, body string) {
+	if body == "" {
+		return
+	}
+	fmt.Fprintf(b, "\n<%s>\n%s\n</%s>\n", tag, body, tag)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// readPassphrase prompts on stderr and reads a passphrase from the terminal
+// without echoing it. It falls back to a plain stdin read when stdin isn't
+// a terminal (e.g. piped input in scripts/tests).
+func readPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		var passphrase string
+		if _, err := fmt.Fscanln(os.Stdin, &passphrase); err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %v", err)
+		}
+		return passphrase, nil
+	}
+
+	passphraseBytes, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %v", err)
+	}
+
+	return string(passphraseBytes), nil
+}
@@ -0,0 +1,189 @@
+// Package store persists fetched Amnezia profiles (the decoded server
+// config plus any locally-generated private keys) to disk, encrypted at
+// rest with a user-supplied passphrase. Private keys never leave the
+// client and cannot be recovered from the server, so this is the only copy
+// that survives between runs.
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	saltSize = 16
+	keySize  = 32
+
+	// scrypt parameters per golang.org/x/crypto/scrypt's recommendation
+	// for interactive logins (as of 2017).
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// Profile is everything needed to reconstruct a previously fetched Amnezia
+// connection: the vpn:// key used to register (so rotate can re-register),
+// the raw decoded config, and the key material generated for each
+// container, keyed by "<index>-<type>".
+type Profile struct {
+	Name      string                       `json:"name"`
+	VPNKey    string                       `json:"vpn_key"`
+	RawConfig json.RawMessage              `json:"raw_config"`
+	KeyPairs  map[string]map[string]string `json:"key_pairs"`
+	CreatedAt time.Time                    `json:"created_at"`
+}
+
+// Vault stores Profiles on disk, one file per profile, each independently
+// encrypted with a key derived from the caller-supplied passphrase.
+type Vault struct {
+	Dir string
+}
+
+// NewVault returns a Vault rooted at dir. The directory is created lazily
+// on first Save.
+func NewVault(dir string) *Vault {
+	return &Vault{Dir: dir}
+}
+
+func (v *Vault) path(name string) string {
+	return filepath.Join(v.Dir, name+".vault")
+}
+
+// Save encrypts profile with a key derived from passphrase and writes it to
+// <profile.Name>.vault in the vault directory, overwriting any existing
+// profile of the same name.
+func (v *Vault) Save(profile Profile, passphrase string) error {
+	if profile.Name == "" {
+		return errors.New("profile name cannot be empty")
+	}
+
+	if err := os.MkdirAll(v.Dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create vault directory: %v", err)
+	}
+
+	plaintext, err := json.Marshal(profile)
+	if err != nil {
+		return fmt.Errorf("failed to serialize profile: %v", err)
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %v", err)
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
+
+	out := make([]byte, 0, len(salt)+len(nonce)+len(ciphertext))
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	out = append(out, ciphertext...)
+
+	return os.WriteFile(v.path(profile.Name), out, 0o600)
+}
+
+// Load decrypts and returns the named profile. It fails with a generic
+// error on a wrong passphrase, matching AES-GCM's all-or-nothing
+// authentication: there is no way to tell "wrong passphrase" from
+// "corrupted file" without leaking an oracle.
+func (v *Vault) Load(name, passphrase string) (*Profile, error) {
+	raw, err := os.ReadFile(v.path(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile %q: %v", name, err)
+	}
+	if len(raw) < saltSize {
+		return nil, fmt.Errorf("profile %q is corrupt", name)
+	}
+	salt := raw[:saltSize]
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < saltSize+gcm.NonceSize() {
+		return nil, fmt.Errorf("profile %q is corrupt", name)
+	}
+	nonce := raw[saltSize : saltSize+gcm.NonceSize()]
+	ciphertext := raw[saltSize+gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt profile %q: wrong passphrase or corrupt data", name)
+	}
+
+	var profile Profile
+	if err := json.Unmarshal(plaintext, &profile); err != nil {
+		return nil, fmt.Errorf("failed to parse profile %q: %v", name, err)
+	}
+
+	return &profile, nil
+}
+
+// List returns the names of all profiles in the vault, in no particular
+// order. It returns an empty list rather than an error if the vault
+// directory does not exist yet.
+func (v *Vault) List() ([]string, error) {
+	entries, err := os.ReadDir(v.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read vault directory: %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".vault" {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(entry.Name(), ".vault"))
+	}
+
+	return names, nil
+}
+
+// Delete removes the named profile from the vault.
+func (v *Vault) Delete(name string) error {
+	if err := os.Remove(v.path(name)); err != nil {
+		return fmt.Errorf("failed to delete profile %q: %v", name, err)
+	}
+	return nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %v", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %v", err)
+	}
+
+	return gcm, nil
+}
@@ -0,0 +1,248 @@
+// Package transport provides a configurable HTTP client for talking to the
+// Amnezia API in the exact censored-network conditions Amnezia targets:
+// transient failures that deserve a retry, and networks where a direct
+// connection to the API is blocked outright and must instead ride over Tor
+// or behind a fronting CDN.
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// Config describes how a Client should dial the network.
+type Config struct {
+	// Timeout bounds a single request attempt. Each retry Post makes gets
+	// its own Timeout; it does not bound the whole retry budget. Defaults
+	// to 30s.
+	Timeout time.Duration
+	// MaxRetries is how many additional attempts Post makes after the first
+	// one fails with a transient network error or a 5xx response. Defaults
+	// to 3.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry; it doubles after
+	// each subsequent attempt. Defaults to 500ms.
+	InitialBackoff time.Duration
+
+	// ProxyURL, if set, routes all requests through this proxy. A
+	// socks5:// URL (e.g. socks5://127.0.0.1:9050) dials through a SOCKS5
+	// proxy such as Tor; any other scheme is treated as an HTTP(S) CONNECT
+	// proxy.
+	ProxyURL string
+	// DomainFront, if set, performs domain fronting: the TLS ClientHello's
+	// SNI (and the DNS lookup and TCP connection) targets DomainFront,
+	// while the HTTP Host header still names the real API endpoint. This
+	// only works against endpoints actually fronted by the same CDN as
+	// DomainFront. With an HTTP(S) ProxyURL, requests go through the
+	// proxy's tunnel to the real endpoint and DomainFront has no effect.
+	DomainFront string
+	// PinnedFingerprints, if set, restricts accepted server certificates
+	// to ones whose SHA-256 fingerprint appears in this list, instead of
+	// trusting the system root store.
+	PinnedFingerprints [][]byte
+
+	// RoundTripper, if set, replaces the built-in transport entirely -
+	// ProxyURL, DomainFront and PinnedFingerprints are then the caller's
+	// responsibility. This is the extension point for plugging in
+	// something like uTLS for JA3 fingerprint mimicry.
+	RoundTripper http.RoundTripper
+}
+
+// Client wraps an *http.Client with retry-with-backoff semantics.
+type Client struct {
+	httpClient     *http.Client
+	maxRetries     int
+	initialBackoff time.Duration
+}
+
+// NewClient builds a Client from cfg, applying defaults for any zero
+// values.
+func NewClient(cfg Config) (*Client, error) {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	maxRetries := cfg.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = 3
+	}
+	initialBackoff := cfg.InitialBackoff
+	if initialBackoff == 0 {
+		initialBackoff = 500 * time.Millisecond
+	}
+
+	roundTripper, err := buildRoundTripper(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		httpClient:     &http.Client{Transport: roundTripper, Timeout: timeout},
+		maxRetries:     maxRetries,
+		initialBackoff: initialBackoff,
+	}, nil
+}
+
+func buildRoundTripper(cfg Config) (http.RoundTripper, error) {
+	if cfg.RoundTripper != nil {
+		return cfg.RoundTripper, nil
+	}
+
+	base := &http.Transport{}
+
+	if len(cfg.PinnedFingerprints) > 0 {
+		base.TLSClientConfig = &tls.Config{
+			// Certificate trust is established entirely by
+			// VerifyPeerCertificate below, against the pinned
+			// fingerprints, rather than the system root store.
+			InsecureSkipVerify:    true,
+			VerifyPeerCertificate: pinnedFingerprintVerifier(cfg.PinnedFingerprints),
+		}
+	}
+
+	if cfg.ProxyURL != "" {
+		if err := applyProxy(base, cfg.ProxyURL); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.DomainFront != "" {
+		if err := applyDomainFronting(base, cfg.DomainFront); err != nil {
+			return nil, err
+		}
+	}
+
+	return base, nil
+}
+
+func applyProxy(base *http.Transport, rawProxyURL string) error {
+	proxyURL, err := url.Parse(rawProxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL %q: %v", rawProxyURL, err)
+	}
+
+	if proxyURL.Scheme != "socks5" {
+		base.Proxy = http.ProxyURL(proxyURL)
+		return nil
+	}
+
+	var auth *proxy.Auth
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+	}
+
+	dialer, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, proxy.Direct)
+	if err != nil {
+		return fmt.Errorf("failed to configure SOCKS5 proxy %q: %v", rawProxyURL, err)
+	}
+
+	base.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.Dial(network, addr)
+	}
+
+	return nil
+}
+
+func applyDomainFronting(base *http.Transport, front string) error {
+	base.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dial address %q: %v", addr, err)
+		}
+
+		// Dial through the SOCKS5 proxy when applyProxy installed one.
+		dial := (&net.Dialer{}).DialContext
+		if base.DialContext != nil {
+			dial = base.DialContext
+		}
+		rawConn, err := dial(ctx, network, net.JoinHostPort(front, port))
+		if err != nil {
+			return nil, fmt.Errorf("failed to dial fronting domain %q: %v", front, err)
+		}
+
+		tlsConfig := &tls.Config{ServerName: front}
+		if base.TLSClientConfig != nil {
+			tlsConfig = base.TLSClientConfig.Clone()
+			tlsConfig.ServerName = front
+		}
+
+		tlsConn := tls.Client(rawConn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			rawConn.Close()
+			return nil, fmt.Errorf("TLS handshake with fronting domain %q failed: %v", front, err)
+		}
+
+		return tlsConn, nil
+	}
+
+	return nil
+}
+
+func pinnedFingerprintVerifier(fingerprints [][]byte) func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		for _, rawCert := range rawCerts {
+			sum := sha256.Sum256(rawCert)
+			for _, pinned := range fingerprints {
+				if bytes.Equal(sum[:], pinned) {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("no presented certificate matched a pinned fingerprint")
+	}
+}
+
+// Post sends a POST request with the given already-serialized JSON body and
+// headers, retrying on transient network errors and 5xx responses with
+// exponential backoff. The caller must close resp.Body on success.
+func (c *Client) Post(ctx context.Context, rawURL string, jsonBody []byte, headers map[string]string) (*http.Response, error) {
+	backoff := c.initialBackoff
+
+	var lastErr error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(jsonBody))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP request: %v", err)
+		}
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil && resp.StatusCode < 500 {
+			return resp, nil
+		}
+
+		if err != nil {
+			lastErr = err
+		} else {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			lastErr = fmt.Errorf("server returned %d: %s", resp.StatusCode, string(bodyBytes))
+		}
+
+		if attempt == c.maxRetries {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+
+	return nil, fmt.Errorf("request to %s failed after %d attempts: %v", rawURL, c.maxRetries+1, lastErr)
+}
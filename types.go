@@ -0,0 +1,28 @@
+package main
+
+// RequestBody is the payload sent to the Amnezia API to register this
+// device's public key and receive a server config in return.
+type RequestBody struct {
+	PublicKey  string `json:"public_key"`
+	OSVersion  string `json:"os_version"`
+	AppVersion string `json:"app_version"`
+	UUID       string `json:"uuid"`
+}
+
+// ResponseBody is the Amnezia API's registration response.
+type ResponseBody struct {
+	Config string `json:"config"`
+}
+
+// Config is the decoded, decompressed registration response: one or more
+// VPN containers configured on the user's Amnezia server.
+type Config struct {
+	Containers []Container `json:"containers"`
+}
+
+// DecodedData is the payload embedded in a vpn:// key: where to register
+// this device and the API key to authenticate with.
+type DecodedData struct {
+	APIEndpoint string `json:"api_endpoint"`
+	APIKey      string `json:"api_key"`
+}
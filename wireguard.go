@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WireGuardInterface is the [Interface] section of a wg-quick config. The
+// Jc/Jmin/Jmax/S1/S2/H1-H4 fields are AmneziaWG's obfuscation parameters and
+// are left empty for plain WireGuard containers.
+type WireGuardInterface struct {
+	PrivateKey string
+	Address    string
+	DNS        string
+	MTU        string
+	Jc         string
+	Jmin       string
+	Jmax       string
+	S1         string
+	S2         string
+	H1         string
+	H2         string
+	H3         string
+	H4         string
+}
+
+// WireGuardPeer is the [Peer] section of a wg-quick config.
+type WireGuardPeer struct {
+	PublicKey           string
+	PresharedKey        string
+	AllowedIPs          string
+	Endpoint            string
+	PersistentKeepalive string
+}
+
+// WireGuardConfig is a typed, round-trippable representation of a
+// wg-quick/AmneziaWG config.
+type WireGuardConfig struct {
+	Interface WireGuardInterface
+	Peer      WireGuardPeer
+}
+
+// ParseWireGuardConfig parses a wg-quick-style INI config, as returned
+// (with placeholders already substituted) by the WireGuard and AWG
+// containers, into a typed WireGuardConfig.
+func ParseWireGuardConfig(raw string) (*WireGuardConfig, error) {
+	cfg := &WireGuardConfig{}
+	section := ""
+
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.Trim(line, "[]"))
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch section {
+		case "interface":
+			cfg.Interface.set(key, value)
+		case "peer":
+			cfg.Peer.set(key, value)
+		}
+	}
+
+	return cfg, nil
+}
+
+func (i *WireGuardInterface) set(key, value string) {
+	switch strings.ToLower(key) {
+	case "privatekey":
+		i.PrivateKey = value
+	case "address":
+		i.Address = value
+	case "dns":
+		i.DNS = value
+	case "mtu":
+		i.MTU = value
+	case "jc":
+		i.Jc = value
+	case "jmin":
+		i.Jmin = value
+	case "jmax":
+		i.Jmax = value
+	case "s1":
+		i.S1 = value
+	case "s2":
+		i.S2 = value
+	case "h1":
+		i.H1 = value
+	case "h2":
+		i.H2 = value
+	case "h3":
+		i.H3 = value
+	case "h4":
+		i.H4 = value
+	}
+}
+
+func (p *WireGuardPeer) set(key, value string) {
+	switch strings.ToLower(key) {
+	case "publickey":
+		p.PublicKey = value
+	case "presharedkey":
+		p.PresharedKey = value
+	case "allowedips":
+		p.AllowedIPs = value
+	case "endpoint":
+		p.Endpoint = value
+	case "persistentkeepalive":
+		p.PersistentKeepalive = value
+	}
+}
+
+// Render serializes the config back into the canonical wg-quick INI format,
+// ready to be piped into `wg-quick up`.
+func (c *WireGuardConfig) Render() string {
+	var b strings.Builder
+
+	b.WriteString("[Interface]\n")
+	writeINIField(&b, "PrivateKey", c.Interface.PrivateKey)
+	writeINIField(&b, "Address", c.Interface.Address)
+	writeINIField(&b, "DNS", c.Interface.DNS)
+	writeINIField(&b, "MTU", c.Interface.MTU)
+	writeINIField(&b, "Jc", c.Interface.Jc)
+	writeINIField(&b, "Jmin", c.Interface.Jmin)
+	writeINIField(&b, "Jmax", c.Interface.Jmax)
+	writeINIField(&b, "S1", c.Interface.S1)
+	writeINIField(&b, "S2", c.Interface.S2)
+	writeINIField(&b, "H1", c.Interface.H1)
+	writeINIField(&b, "H2", c.Interface.H2)
+	writeINIField(&b, "H3", c.Interface.H3)
+	writeINIField(&b, "H4", c.Interface.H4)
+
+	b.WriteString("\n[Peer]\n")
+	writeINIField(&b, "PublicKey", c.Peer.PublicKey)
+	writeINIField(&b, "PresharedKey", c.Peer.PresharedKey)
+	writeINIField(&b, "AllowedIPs", c.Peer.AllowedIPs)
+	writeINIField(&b, "Endpoint", c.Peer.Endpoint)
+	writeINIField(&b, "PersistentKeepalive", c.Peer.PersistentKeepalive)
+
+	return b.String()
+}
+
+func writeINIField(b *strings.Builder, key, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(b, "%s = %s\n", key, value)
+}
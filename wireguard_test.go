@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestWireGuardConfigRoundTrip(t *testing.T) {
+	raw := `[Interface]
+PrivateKey = cHJpdmF0ZWtleQ==
+Address = 10.8.0.2/24
+DNS = 1.1.1.1
+MTU = 1380
+Jc = 4
+Jmin = 40
+Jmax = 70
+S1 = 45
+S2 = 65
+H1 = 1
+H2 = 2
+H3 = 3
+H4 = 4
+
+[Peer]
+PublicKey = cHVibGlja2V5
+PresharedKey = cHJlc2hhcmVka2V5
+AllowedIPs = 0.0.0.0/0
+Endpoint = vpn.example.com:51820
+PersistentKeepalive = 25
+`
+
+	cfg, err := ParseWireGuardConfig(raw)
+	if err != nil {
+		t.Fatalf("ParseWireGuardConfig: %v", err)
+	}
+
+	want := WireGuardInterface{
+		PrivateKey: "cHJpdmF0ZWtleQ==",
+		Address:    "10.8.0.2/24",
+		DNS:        "1.1.1.1",
+		MTU:        "1380",
+		Jc:         "4",
+		Jmin:       "40",
+		Jmax:       "70",
+		S1:         "45",
+		S2:         "65",
+		H1:         "1",
+		H2:         "2",
+		H3:         "3",
+		H4:         "4",
+	}
+	if cfg.Interface != want {
+		t.Errorf("Interface = %+v, want %+v", cfg.Interface, want)
+	}
+
+	wantPeer := WireGuardPeer{
+		PublicKey:           "cHVibGlja2V5",
+		PresharedKey:        "cHJlc2hhcmVka2V5",
+		AllowedIPs:          "0.0.0.0/0",
+		Endpoint:            "vpn.example.com:51820",
+		PersistentKeepalive: "25",
+	}
+	if cfg.Peer != wantPeer {
+		t.Errorf("Peer = %+v, want %+v", cfg.Peer, wantPeer)
+	}
+
+	reparsed, err := ParseWireGuardConfig(cfg.Render())
+	if err != nil {
+		t.Fatalf("ParseWireGuardConfig(Render()): %v", err)
+	}
+	if *reparsed != *cfg {
+		t.Errorf("round-tripped config = %+v, want %+v", *reparsed, *cfg)
+	}
+}